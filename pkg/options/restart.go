@@ -2,9 +2,11 @@ package options
 
 import (
 	"fmt"
+	"math/rand"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -16,10 +18,160 @@ import (
 const (
 	DefaultRetryCount      = 3
 	DefaultRestartDuration = 3
+
+	DefaultRestartCondition  = "on-failure"
+	DefaultRestartDelay      = 5 * time.Second
+	DefaultRestartWindow     = time.Minute
+	DefaultRestartMaxAttempt = 3
+	DefaultRestartBackoff    = "constant"
+
+	restartBackoffJitterFraction = 0.2
+
+	DefaultParallelism = 1
 )
 
 var AvailabilityModes = []string{"strong", "weak", "force"}
 
+// RestartConditions are the only values RestartPolicy.Condition accepts.
+// "any" was dropped: this isn't a long-running process with an exit status
+// to watch like Docker/Swarm's restart policy it was borrowed from, so
+// "any" would retry through exactly the same path as "on-failure" with no
+// way to tell the two apart. Re-add it only alongside an actual behavioral
+// difference.
+var RestartConditions = []string{"none", "on-failure"}
+
+var RestartBackoffs = []string{"constant", "linear", "exponential"}
+
+// RestartPolicy is the declarative, Swarm/Docker-style policy that governs how
+// the rolling-restart loop retries a single node: how long to wait between
+// attempts, how that wait grows, and how many failures within a rolling
+// window are tolerated before the whole rollout aborts.
+type RestartPolicy struct {
+	Condition   string
+	Delay       time.Duration
+	Window      time.Duration
+	MaxAttempts int
+	Backoff     string
+	Jitter      bool
+}
+
+func (p RestartPolicy) Validate() error {
+	if !util.Contains(RestartConditions, p.Condition) {
+		return fmt.Errorf("specified not supported restart condition: %s", p.Condition)
+	}
+
+	if !util.Contains(RestartBackoffs, p.Backoff) {
+		return fmt.Errorf("specified not supported restart backoff: %s", p.Backoff)
+	}
+
+	if p.Delay < 0 {
+		return fmt.Errorf("specified invalid restart delay: %s. Must be positive", p.Delay)
+	}
+
+	if p.Window < 0 {
+		return fmt.Errorf("specified invalid restart window: %s. Must be positive", p.Window)
+	}
+
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("specified invalid restart max attempts: %d. Must be positive", p.MaxAttempts)
+	}
+
+	return nil
+}
+
+// NextDelay returns how long to wait before the attempt-th retry (1-indexed)
+// of the same node, following the configured backoff curve. Exponential
+// backoff doubles the delay every attempt; linear backoff grows it by the
+// base delay every attempt; constant backoff always waits the base delay.
+// With Jitter set, a random amount of up to 20% of that delay is added.
+func (p RestartPolicy) NextDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	var delay time.Duration
+	switch p.Backoff {
+	case "linear":
+		delay = p.Delay * time.Duration(attempt)
+	case "exponential":
+		delay = p.Delay * time.Duration(uint64(1)<<uint(attempt-1))
+	default:
+		delay = p.Delay
+	}
+
+	if p.Jitter {
+		delay += time.Duration(rand.Float64() * float64(delay) * restartBackoffJitterFraction)
+	}
+
+	return delay
+}
+
+// CMSHoldDuration is how long CMS should keep a node under maintenance for a
+// single rolling-restart invocation: enough time to exhaust the configured
+// number of attempts at the configured delay, plus a safety margin so a slow
+// last attempt is not cut off.
+func (p RestartPolicy) CMSHoldDuration() time.Duration {
+	const safetyMargin = 30 * time.Second
+
+	maxAttempts := p.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return p.Delay*time.Duration(maxAttempts) + safetyMargin
+}
+
+// nodeAttempt is a single recorded restart attempt for a node, used to
+// evaluate the rolling window.
+type nodeAttempt struct {
+	at      time.Time
+	success bool
+}
+
+// RestartHistory keeps per-node attempt history in memory so window
+// semantics (N failures per node within a rolling window) can be honored
+// across the lifetime of a single rolling-restart invocation, including one
+// resumed via --continue. The history is not persisted: attempts made by an
+// earlier ydbops process are not seen by a later one.
+type RestartHistory struct {
+	mu       sync.Mutex
+	attempts map[string][]nodeAttempt
+}
+
+func NewRestartHistory() *RestartHistory {
+	return &RestartHistory{
+		attempts: make(map[string][]nodeAttempt),
+	}
+}
+
+// RecordAttempt appends an attempt for the given node, keyed by its FQDN or
+// node id, at the given time.
+func (h *RestartHistory) RecordAttempt(node string, at time.Time, success bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.attempts[node] = append(h.attempts[node], nodeAttempt{at: at, success: success})
+}
+
+// FailuresInWindow reports how many failed attempts were recorded for node
+// within [now-window, now].
+func (h *RestartHistory) FailuresInWindow(node string, now time.Time, window time.Duration) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := 0
+	for _, a := range h.attempts[node] {
+		if a.success {
+			continue
+		}
+		if now.Sub(a.at) <= window {
+			count++
+		}
+	}
+
+	return count
+}
+
 type RestartOptions struct {
 	CMS *CMS
 
@@ -30,6 +182,15 @@ type RestartOptions struct {
 	RestartDuration    int
 	RestartRetryNumber int
 
+	RestartCondition   string
+	RestartDelay       time.Duration
+	RestartWindow      time.Duration
+	RestartMaxAttempts int
+	RestartBackoff     string
+	RestartJitter      bool
+
+	Parallelism int
+
 	Continue bool
 }
 
@@ -50,6 +211,14 @@ func (o *RestartOptions) Validate() error {
 		return fmt.Errorf("specified invalid restart retry number: %d. Must be positive", o.RestartRetryNumber)
 	}
 
+	if err := o.GetRestartPolicy().Validate(); err != nil {
+		return err
+	}
+
+	if o.Parallelism < 1 {
+		return fmt.Errorf("specified invalid parallelism: %d. Must be at least 1", o.Parallelism)
+	}
+
 	_, errFromIds := o.GetNodeIds()
 	_, errFromFQDNs := o.GetNodeFQDNs()
 	if errFromIds != nil && errFromFQDNs != nil {
@@ -77,11 +246,35 @@ so use at your own risk.`)
 	fs.StringVarP(&o.AvailabilityMode, "availability-mode", "", AvailabilityModes[0],
 		fmt.Sprintf("Availability mode. Available choices: %s", strings.Join(AvailabilityModes, ", ")))
 
-	fs.IntVar(&o.RestartDuration, "restart-duration", DefaultRestartDuration, `CMS will release the node for maintenance for restart-duration * restart-retry-number seconds. Any maintenance
-after that would be considered a regular cluster failure`)
+	fs.IntVar(&o.RestartDuration, "restart-duration", DefaultRestartDuration,
+		"Deprecated, kept for backwards compatibility and otherwise unused: CMS hold duration is now derived from --restart-delay and --restart-max-attempts")
+	_ = fs.MarkDeprecated("restart-duration", "the CMS hold duration is now derived from --restart-delay and --restart-max-attempts")
 
 	fs.IntVarP(&o.RestartRetryNumber, "restart-retry-number", "", DefaultRetryCount,
-		fmt.Sprintf("How many times every node should be retried on error, default %v", DefaultRetryCount))
+		"Deprecated, kept for backwards compatibility and otherwise unused: use --restart-max-attempts instead")
+	_ = fs.MarkDeprecated("restart-retry-number", "use --restart-max-attempts instead")
+
+	fs.StringVarP(&o.RestartCondition, "restart-condition", "", DefaultRestartCondition,
+		fmt.Sprintf("When to retry a node restart. Available choices: %s", strings.Join(RestartConditions, ", ")))
+
+	fs.DurationVar(&o.RestartDelay, "restart-delay", DefaultRestartDelay,
+		"Delay between restart attempts for the same node")
+
+	fs.DurationVar(&o.RestartWindow, "restart-window", DefaultRestartWindow,
+		"Rolling window over which --restart-max-attempts failures per node are tolerated before the rollout aborts")
+
+	fs.IntVar(&o.RestartMaxAttempts, "restart-max-attempts", DefaultRestartMaxAttempt,
+		"How many attempt failures per node are tolerated within --restart-window before the rollout aborts")
+
+	fs.StringVarP(&o.RestartBackoff, "restart-backoff", "", DefaultRestartBackoff,
+		fmt.Sprintf("How --restart-delay grows between attempts. Available choices: %s", strings.Join(RestartBackoffs, ", ")))
+
+	fs.BoolVar(&o.RestartJitter, "restart-jitter", false, "Add random jitter on top of the computed restart backoff delay")
+
+	fs.IntVar(&o.Parallelism, "parallelism", DefaultParallelism,
+		`How many nodes to request CMS maintenance permits for and restart simultaneously, subject to the
+constraints of --availability-mode: strong mode still serializes per nodegroup, weak mode may fan out
+across fault domains, force mode may saturate up to this many nodes regardless`)
 
 	fs.StringSliceVar(&o.Tenants, "tenants", o.Tenants, "Restart only specified tenants")
 
@@ -99,8 +292,24 @@ func (o *RestartOptions) GetAvailabilityMode() Ydb_Maintenance.AvailabilityMode
 	return Ydb_Maintenance.AvailabilityMode(value)
 }
 
+// GetRestartPolicy builds the RestartPolicy value object consumed by the
+// rolling-restart loop from the flags parsed into this RestartOptions.
+func (o *RestartOptions) GetRestartPolicy() RestartPolicy {
+	return RestartPolicy{
+		Condition:   o.RestartCondition,
+		Delay:       o.RestartDelay,
+		Window:      o.RestartWindow,
+		MaxAttempts: o.RestartMaxAttempts,
+		Backoff:     o.RestartBackoff,
+		Jitter:      o.RestartJitter,
+	}
+}
+
+// GetRestartDuration returns the CMS hold duration for a single node,
+// derived from the restart policy's delay and max-attempts rather than the
+// legacy restart-duration/restart-retry-number product.
 func (o *RestartOptions) GetRestartDuration() *durationpb.Duration {
-	return durationpb.New(time.Second * time.Duration(o.RestartDuration) * time.Duration(o.RestartRetryNumber))
+	return durationpb.New(o.GetRestartPolicy().CMSHoldDuration())
 }
 
 func (o *RestartOptions) GetNodeFQDNs() ([]string, error) {
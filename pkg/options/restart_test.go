@@ -0,0 +1,110 @@
+package options
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartPolicyNextDelay(t *testing.T) {
+	cases := []struct {
+		name    string
+		policy  RestartPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"constant first attempt", RestartPolicy{Backoff: "constant", Delay: time.Second}, 1, time.Second},
+		{"constant later attempt", RestartPolicy{Backoff: "constant", Delay: time.Second}, 5, time.Second},
+		{"linear grows by base delay", RestartPolicy{Backoff: "linear", Delay: time.Second}, 3, 3 * time.Second},
+		{"exponential doubles", RestartPolicy{Backoff: "exponential", Delay: time.Second}, 4, 8 * time.Second},
+		{"clamps attempt below one", RestartPolicy{Backoff: "exponential", Delay: time.Second}, 0, time.Second},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.policy.NextDelay(c.attempt); got != c.want {
+				t.Fatalf("NextDelay(%d) = %s, want %s", c.attempt, got, c.want)
+			}
+		})
+	}
+}
+
+func TestRestartPolicyNextDelayJitterAddsOnTop(t *testing.T) {
+	policy := RestartPolicy{Backoff: "constant", Delay: time.Second, Jitter: true}
+
+	got := policy.NextDelay(1)
+	if got <= time.Second {
+		t.Fatalf("expected jitter to push delay above the base delay, got %s", got)
+	}
+}
+
+func TestRestartPolicyValidate(t *testing.T) {
+	base := RestartPolicy{Condition: "on-failure", Backoff: "constant", Delay: time.Second, Window: time.Minute, MaxAttempts: 3}
+
+	if err := base.Validate(); err != nil {
+		t.Fatalf("expected valid policy to pass, got %v", err)
+	}
+
+	invalidCondition := base
+	invalidCondition.Condition = "sometimes"
+	if err := invalidCondition.Validate(); err == nil {
+		t.Fatalf("expected invalid condition to be rejected")
+	}
+
+	invalidBackoff := base
+	invalidBackoff.Backoff = "magic"
+	if err := invalidBackoff.Validate(); err == nil {
+		t.Fatalf("expected invalid backoff to be rejected")
+	}
+
+	negativeDelay := base
+	negativeDelay.Delay = -time.Second
+	if err := negativeDelay.Validate(); err == nil {
+		t.Fatalf("expected negative delay to be rejected")
+	}
+}
+
+func TestRestartHistoryFailuresInWindow(t *testing.T) {
+	history := NewRestartHistory()
+	now := time.Unix(1_700_000_000, 0)
+
+	history.RecordAttempt("node-a", now.Add(-90*time.Second), false)
+	history.RecordAttempt("node-a", now.Add(-40*time.Second), false)
+	history.RecordAttempt("node-a", now.Add(-10*time.Second), true)
+	history.RecordAttempt("node-b", now.Add(-5*time.Second), false)
+
+	if got := history.FailuresInWindow("node-a", now, time.Minute); got != 1 {
+		t.Fatalf("expected 1 failure within the last minute for node-a, got %d", got)
+	}
+
+	if got := history.FailuresInWindow("node-a", now, 2*time.Minute); got != 2 {
+		t.Fatalf("expected 2 failures within the last two minutes for node-a, got %d", got)
+	}
+
+	if got := history.FailuresInWindow("node-b", now, time.Minute); got != 1 {
+		t.Fatalf("expected 1 failure for node-b, got %d", got)
+	}
+
+	if got := history.FailuresInWindow("node-c", now, time.Minute); got != 0 {
+		t.Fatalf("expected 0 failures for a node with no recorded attempts, got %d", got)
+	}
+}
+
+func TestRestartOptionsGetRestartPolicyAndCMSHoldDuration(t *testing.T) {
+	o := &RestartOptions{
+		RestartCondition:   "on-failure",
+		RestartDelay:       10 * time.Second,
+		RestartWindow:      time.Minute,
+		RestartMaxAttempts: 4,
+		RestartBackoff:     "constant",
+	}
+
+	policy := o.GetRestartPolicy()
+	if policy.Delay != o.RestartDelay || policy.MaxAttempts != o.RestartMaxAttempts {
+		t.Fatalf("GetRestartPolicy() did not carry over the configured fields: %+v", policy)
+	}
+
+	want := 10*time.Second*4 + 30*time.Second
+	if got := policy.CMSHoldDuration(); got != want {
+		t.Fatalf("CMSHoldDuration() = %s, want %s", got, want)
+	}
+}
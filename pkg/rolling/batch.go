@@ -0,0 +1,303 @@
+package rolling
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ydb-platform/ydb-go-genproto/draft/protos/Ydb_Maintenance"
+	"github.com/ydb-platform/ydb-ops/pkg/options"
+	"go.uber.org/zap"
+)
+
+// NodeRestarter is implemented by every per-flavor restarter (baremetal,
+// k8s, ...) and is the unit of work a Batch dispatches concurrently.
+type NodeRestarter interface {
+	RestartNode(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node) error
+}
+
+// PermitRequester abstracts the CMS maintenance-permit protocol so Batch can
+// be driven against a fake Ydb_Maintenance server in tests.
+type PermitRequester interface {
+	RequestPermit(ctx context.Context, node *Ydb_Maintenance.Node) error
+
+	// ReleasePermit hands the permit back to CMS, reporting whether the node
+	// came out of maintenance healthy (success) or is being released after a
+	// failed restart/health check, so CMS can treat the action accordingly
+	// (e.g. CompleteAction with a failure) instead of always being told the
+	// restart went fine.
+	ReleasePermit(ctx context.Context, node *Ydb_Maintenance.Node, success bool) error
+}
+
+// NodeResult is the outcome of restarting a single node as part of a Batch.
+type NodeResult struct {
+	Node *Ydb_Maintenance.Node
+	Err  error
+}
+
+// Summary aggregates the per-node outcomes of a Batch run.
+type Summary struct {
+	Results []NodeResult
+}
+
+func (s Summary) Failed() []NodeResult {
+	failed := make([]NodeResult, 0)
+	for _, r := range s.Results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+
+	return failed
+}
+
+// Batch dispatches RestartNode calls for a set of nodes concurrently, up to
+// Parallelism at a time, honoring the nodegroup/fault-domain invariants of
+// AvailabilityMode:
+//   - strong: at most one in-flight restart per nodegroup at a time
+//   - weak: at most one in-flight restart per fault domain at a time
+//   - force: up to Parallelism in-flight restarts, no domain constraint
+type Batch struct {
+	Parallelism      int
+	AvailabilityMode Ydb_Maintenance.AvailabilityMode
+
+	// NodeGroup returns the key a node must not share an in-flight restart
+	// with under strong/weak mode: a nodegroup id for strong mode, a fault
+	// domain id for weak mode. Ignored under force mode.
+	NodeGroup func(node *Ydb_Maintenance.Node) string
+
+	// Policy governs per-node retries: how long to wait between attempts,
+	// how that wait grows, and how many failures within Policy.Window are
+	// tolerated before this node is given up on. The zero value makes a
+	// single attempt per node and does not retry.
+	Policy options.RestartPolicy
+
+	// History records every attempt against RestartPolicy's window, so a
+	// node seen again by a Batch sharing the same History within this
+	// process keeps its prior attempts. It lives in memory only: attempts
+	// from an earlier process (e.g. before --continue) are not included. A
+	// nil History counts only the failures of the current restartWithPolicy
+	// call and ignores Policy.Window.
+	History *options.RestartHistory
+}
+
+// Run requests a permit and restarts each of nodes, respecting b's
+// concurrency invariants, and returns once every node has either finished
+// or been abandoned because of a graceful drain. A failure in one worker
+// stops new permits from being requested but lets in-flight restarts run to
+// completion rather than aborting them.
+func (b Batch) Run(ctx context.Context, logger *zap.SugaredLogger, permits PermitRequester, restarter NodeRestarter, nodes []*Ydb_Maintenance.Node) Summary {
+	parallelism := b.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	serialized := b.AvailabilityMode != Ydb_Maintenance.AvailabilityMode_AVAILABILITY_MODE_FORCE
+
+	domains := make([]string, len(nodes))
+	for i, node := range nodes {
+		if serialized && b.NodeGroup != nil {
+			domains[i] = b.NodeGroup(node)
+		} else {
+			// Every node is its own domain: nothing should block it against
+			// any other node.
+			domains[i] = fmt.Sprintf("\x00unconstrained-%d", i)
+		}
+	}
+
+	sched := newDomainScheduler(nodes, domains)
+
+	workers := parallelism
+	if workers > len(nodes) {
+		workers = len(nodes)
+	}
+
+	var draining atomic.Bool
+	results := make([]NodeResult, len(nodes))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				item, domain, ok := sched.next()
+				if !ok {
+					return
+				}
+
+				if draining.Load() {
+					results[item.index] = NodeResult{Node: item.node, Err: fmt.Errorf("restart skipped: batch is draining after an earlier failure")}
+					sched.done(domain)
+					continue
+				}
+
+				err := b.restartOne(ctx, logger, permits, restarter, item.node)
+				results[item.index] = NodeResult{Node: item.node, Err: err}
+				if err != nil {
+					draining.Store(true)
+				}
+
+				sched.done(domain)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return Summary{Results: results}
+}
+
+// queuedNode pairs a node with its position in the caller's original slice,
+// so workers that pull nodes out of domain order can still write results
+// back to the right index.
+type queuedNode struct {
+	index int
+	node  *Ydb_Maintenance.Node
+}
+
+// domainScheduler hands nodes out to worker goroutines, up to one in-flight
+// node per domain at a time, without ever blocking a worker on a domain that
+// already has a node in flight: a worker whose only runnable candidates
+// belong to busy domains instead waits for any domain to free up, so a
+// domain that's slow to drain never head-of-line-blocks workers that could
+// be making progress on a different, non-conflicting domain. This is what
+// lets weak mode fan out across fault domains (and strong mode across
+// nodegroups) even when the input slice happens to group nodes by domain
+// consecutively, e.g. a list sorted by FQDN or rack.
+type domainScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	order   []string
+	queue   map[string][]queuedNode
+	busy    map[string]bool
+	pending int
+}
+
+func newDomainScheduler(nodes []*Ydb_Maintenance.Node, domains []string) *domainScheduler {
+	s := &domainScheduler{
+		queue: make(map[string][]queuedNode),
+		busy:  make(map[string]bool),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	for i, node := range nodes {
+		domain := domains[i]
+		if _, ok := s.queue[domain]; !ok {
+			s.order = append(s.order, domain)
+		}
+		s.queue[domain] = append(s.queue[domain], queuedNode{index: i, node: node})
+		s.pending++
+	}
+
+	return s
+}
+
+// next blocks until either a node from a domain with nothing currently in
+// flight becomes available, or every node has been handed out, in which
+// case ok is false. Domains are polled round-robin in the order they first
+// appeared, so one congested domain can't starve the others of a turn.
+func (s *domainScheduler) next() (queuedNode, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if s.pending == 0 {
+			return queuedNode{}, "", false
+		}
+
+		for _, domain := range s.order {
+			if s.busy[domain] || len(s.queue[domain]) == 0 {
+				continue
+			}
+
+			item := s.queue[domain][0]
+			s.queue[domain] = s.queue[domain][1:]
+			s.busy[domain] = true
+			s.pending--
+
+			return item, domain, true
+		}
+
+		// Every domain with work left is currently busy: wait for done() to
+		// free one up instead of spinning.
+		s.cond.Wait()
+	}
+}
+
+// done marks domain as no longer having a node in flight and wakes any
+// worker waiting on that capacity to free up.
+func (s *domainScheduler) done(domain string) {
+	s.mu.Lock()
+	s.busy[domain] = false
+	s.mu.Unlock()
+
+	s.cond.Broadcast()
+}
+
+func (b Batch) restartOne(ctx context.Context, logger *zap.SugaredLogger, permits PermitRequester, restarter NodeRestarter, node *Ydb_Maintenance.Node) error {
+	if err := permits.RequestPermit(ctx, node); err != nil {
+		return fmt.Errorf("failed to obtain maintenance permit for %s: %w", node.Host, err)
+	}
+
+	restartErr := b.restartWithPolicy(ctx, logger, restarter, node)
+
+	// The permit is released with the actual outcome of the restart, not
+	// unconditionally: a health-check failure must reach CMS as a failure,
+	// not look identical to a clean restart.
+	if err := permits.ReleasePermit(ctx, node, restartErr == nil); err != nil {
+		logger.Warnf("failed to release maintenance permit for %s: %v", node.Host, err)
+	}
+
+	if restartErr != nil {
+		return fmt.Errorf("failed to restart %s: %w", node.Host, restartErr)
+	}
+
+	return nil
+}
+
+// restartWithPolicy calls restarter.RestartNode, retrying per b.Policy until
+// either it succeeds, b.Policy.Condition is "none", or b.Policy.MaxAttempts
+// failures for this node fall within b.Policy.Window (evaluated against
+// b.History, which holds the attempts recorded in this process only).
+func (b Batch) restartWithPolicy(ctx context.Context, logger *zap.SugaredLogger, restarter NodeRestarter, node *Ydb_Maintenance.Node) error {
+	maxAttempts := b.Policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		lastErr = restarter.RestartNode(ctx, logger, node)
+
+		now := time.Now()
+		if b.History != nil {
+			b.History.RecordAttempt(node.Host, now, lastErr == nil)
+		}
+
+		if lastErr == nil || b.Policy.Condition == "none" {
+			return lastErr
+		}
+
+		failures := attempt
+		if b.History != nil {
+			failures = b.History.FailuresInWindow(node.Host, now, b.Policy.Window)
+		}
+
+		if failures >= maxAttempts {
+			return fmt.Errorf("gave up after %d failed attempt(s) within %s: %w", failures, b.Policy.Window, lastErr)
+		}
+
+		delay := b.Policy.NextDelay(attempt)
+		logger.Debugf("retrying restart of %s in %s (attempt %d failed: %v)", node.Host, delay, attempt, lastErr)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
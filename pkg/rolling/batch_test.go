@@ -0,0 +1,283 @@
+package rolling
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ydb-platform/ydb-go-genproto/draft/protos/Ydb_Maintenance"
+	"go.uber.org/zap"
+)
+
+// fakePermits is a fake Ydb_Maintenance-backed PermitRequester: it hands out
+// permits immediately, like a real CMS would for a cluster with spare
+// capacity, but records the peak number of concurrently outstanding permits
+// overall and per nodegroup/fault-domain, so tests can assert a Batch never
+// exceeds its availability mode's invariant.
+type fakePermits struct {
+	mu             sync.Mutex
+	domainInFlight map[string]int
+	maxPerDomain   int
+
+	totalInFlight int32
+	maxTotal      int32
+
+	releasedWith map[string]bool
+
+	nodeGroup func(node *Ydb_Maintenance.Node) string
+}
+
+func newFakePermits(nodeGroup func(node *Ydb_Maintenance.Node) string) *fakePermits {
+	return &fakePermits{
+		domainInFlight: make(map[string]int),
+		releasedWith:   make(map[string]bool),
+		nodeGroup:      nodeGroup,
+	}
+}
+
+func (f *fakePermits) RequestPermit(ctx context.Context, node *Ydb_Maintenance.Node) error {
+	f.mu.Lock()
+	domain := f.nodeGroup(node)
+	f.domainInFlight[domain]++
+	if f.domainInFlight[domain] > f.maxPerDomain {
+		f.maxPerDomain = f.domainInFlight[domain]
+	}
+	f.mu.Unlock()
+
+	total := atomic.AddInt32(&f.totalInFlight, 1)
+	for {
+		max := atomic.LoadInt32(&f.maxTotal)
+		if total <= max {
+			break
+		}
+		if atomic.CompareAndSwapInt32(&f.maxTotal, max, total) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (f *fakePermits) ReleasePermit(ctx context.Context, node *Ydb_Maintenance.Node, success bool) error {
+	f.mu.Lock()
+	f.domainInFlight[f.nodeGroup(node)]--
+	f.releasedWith[node.Host] = success
+	f.mu.Unlock()
+
+	atomic.AddInt32(&f.totalInFlight, -1)
+
+	return nil
+}
+
+type sleepyRestarter struct {
+	delay time.Duration
+}
+
+func (r sleepyRestarter) RestartNode(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node) error {
+	time.Sleep(r.delay)
+	return nil
+}
+
+func nodesInGroups(count, groups int) ([]*Ydb_Maintenance.Node, func(node *Ydb_Maintenance.Node) string) {
+	nodes := make([]*Ydb_Maintenance.Node, 0, count)
+	group := make(map[string]string, count)
+
+	for i := 0; i < count; i++ {
+		host := fmt.Sprintf("node-%d", i)
+		nodes = append(nodes, &Ydb_Maintenance.Node{Host: host})
+		group[host] = fmt.Sprintf("group-%d", i%groups)
+	}
+
+	return nodes, func(node *Ydb_Maintenance.Node) string { return group[node.Host] }
+}
+
+func TestBatchStrongModeSerializesPerNodeGroup(t *testing.T) {
+	nodes, nodeGroup := nodesInGroups(12, 3)
+	permits := newFakePermits(nodeGroup)
+
+	b := Batch{
+		Parallelism:      4,
+		AvailabilityMode: Ydb_Maintenance.AvailabilityMode_AVAILABILITY_MODE_STRONG,
+		NodeGroup:        nodeGroup,
+	}
+
+	summary := b.Run(context.Background(), zap.NewNop().Sugar(), permits, sleepyRestarter{delay: 10 * time.Millisecond}, nodes)
+
+	if failed := summary.Failed(); len(failed) != 0 {
+		t.Fatalf("expected no failures, got %v", failed)
+	}
+
+	if permits.maxPerDomain > 1 {
+		t.Fatalf("strong mode must serialize per nodegroup, but saw %d concurrent permits in one group", permits.maxPerDomain)
+	}
+}
+
+func TestBatchWeakModeFansOutAcrossFaultDomains(t *testing.T) {
+	nodes, faultDomain := nodesInGroups(12, 4)
+	permits := newFakePermits(faultDomain)
+
+	b := Batch{
+		Parallelism:      4,
+		AvailabilityMode: Ydb_Maintenance.AvailabilityMode_AVAILABILITY_MODE_WEAK,
+		NodeGroup:        faultDomain,
+	}
+
+	summary := b.Run(context.Background(), zap.NewNop().Sugar(), permits, sleepyRestarter{delay: 10 * time.Millisecond}, nodes)
+
+	if failed := summary.Failed(); len(failed) != 0 {
+		t.Fatalf("expected no failures, got %v", failed)
+	}
+
+	if permits.maxPerDomain > 1 {
+		t.Fatalf("weak mode must serialize per fault domain, but saw %d concurrent permits in one domain", permits.maxPerDomain)
+	}
+
+	if permits.maxTotal < 2 {
+		t.Fatalf("weak mode should still fan out across fault domains, but never saw more than %d concurrent permits", permits.maxTotal)
+	}
+}
+
+// nodesInClusteredGroups is like nodesInGroups, but assigns domains in
+// consecutive runs (node 0..k-1 in one domain, node k..2k-1 in the next,
+// and so on) instead of round-robin. A node list sorted by FQDN or rack is
+// far more likely to look like this than to interleave domains, and it's
+// exactly the ordering that used to defeat cross-domain fan-out: the first
+// Parallelism nodes dispatched could all land in the same domain.
+func nodesInClusteredGroups(count, groups int) ([]*Ydb_Maintenance.Node, func(node *Ydb_Maintenance.Node) string) {
+	nodes := make([]*Ydb_Maintenance.Node, 0, count)
+	group := make(map[string]string, count)
+
+	perGroup := count / groups
+	for i := 0; i < count; i++ {
+		host := fmt.Sprintf("node-%d", i)
+		nodes = append(nodes, &Ydb_Maintenance.Node{Host: host})
+		group[host] = fmt.Sprintf("group-%d", i/perGroup)
+	}
+
+	return nodes, func(node *Ydb_Maintenance.Node) string { return group[node.Host] }
+}
+
+func TestBatchWeakModeFansOutAcrossFaultDomainsEvenWhenInputIsClusteredByDomain(t *testing.T) {
+	nodes, faultDomain := nodesInClusteredGroups(12, 3)
+	permits := newFakePermits(faultDomain)
+
+	b := Batch{
+		Parallelism:      4,
+		AvailabilityMode: Ydb_Maintenance.AvailabilityMode_AVAILABILITY_MODE_WEAK,
+		NodeGroup:        faultDomain,
+	}
+
+	summary := b.Run(context.Background(), zap.NewNop().Sugar(), permits, sleepyRestarter{delay: 20 * time.Millisecond}, nodes)
+
+	if failed := summary.Failed(); len(failed) != 0 {
+		t.Fatalf("expected no failures, got %v", failed)
+	}
+
+	if permits.maxPerDomain > 1 {
+		t.Fatalf("weak mode must serialize per fault domain, but saw %d concurrent permits in one domain", permits.maxPerDomain)
+	}
+
+	if permits.maxTotal < 2 {
+		t.Fatalf("weak mode should fan out across fault domains even when the input is clustered by domain (first Parallelism nodes sharing one domain), but never saw more than %d concurrent permits", permits.maxTotal)
+	}
+}
+
+func TestBatchForceModeSaturatesUpToParallelism(t *testing.T) {
+	nodes, faultDomain := nodesInGroups(12, 3)
+	permits := newFakePermits(faultDomain)
+
+	const parallelism = 4
+	b := Batch{
+		Parallelism:      parallelism,
+		AvailabilityMode: Ydb_Maintenance.AvailabilityMode_AVAILABILITY_MODE_FORCE,
+		NodeGroup:        faultDomain,
+	}
+
+	summary := b.Run(context.Background(), zap.NewNop().Sugar(), permits, sleepyRestarter{delay: 10 * time.Millisecond}, nodes)
+
+	if failed := summary.Failed(); len(failed) != 0 {
+		t.Fatalf("expected no failures, got %v", failed)
+	}
+
+	if permits.maxTotal > parallelism {
+		t.Fatalf("expected at most %d concurrent permits, saw %d", parallelism, permits.maxTotal)
+	}
+
+	if permits.maxTotal < parallelism {
+		t.Fatalf("force mode should saturate up to parallelism, but never saw more than %d concurrent permits", permits.maxTotal)
+	}
+}
+
+func TestBatchDrainsGracefullyOnFailure(t *testing.T) {
+	nodes, faultDomain := nodesInGroups(8, 1)
+	permits := newFakePermits(faultDomain)
+
+	failing := &failOnceRestarter{failAfter: 2}
+
+	b := Batch{
+		Parallelism:      3,
+		AvailabilityMode: Ydb_Maintenance.AvailabilityMode_AVAILABILITY_MODE_FORCE,
+		NodeGroup:        faultDomain,
+	}
+
+	summary := b.Run(context.Background(), zap.NewNop().Sugar(), permits, failing, nodes)
+
+	// Only the second RestartNode call fails; every other error is a node
+	// skipped because the batch was draining.
+	failed := summary.Failed()
+	if len(failed) == 0 {
+		t.Fatalf("expected the simulated restart failure to be reported, got none")
+	}
+
+	skipped := len(failed) - 1
+	if skipped == 0 {
+		t.Fatalf("expected nodes dispatched after the failure to be skipped while draining, got none")
+	}
+}
+
+func TestBatchReleasesPermitWithFailureOutcomeOnRestartError(t *testing.T) {
+	nodes, faultDomain := nodesInGroups(1, 1)
+	permits := newFakePermits(faultDomain)
+
+	failing := &failOnceRestarter{failAfter: 1}
+
+	b := Batch{
+		Parallelism:      1,
+		AvailabilityMode: Ydb_Maintenance.AvailabilityMode_AVAILABILITY_MODE_FORCE,
+		NodeGroup:        faultDomain,
+	}
+
+	summary := b.Run(context.Background(), zap.NewNop().Sugar(), permits, failing, nodes)
+
+	if failed := summary.Failed(); len(failed) != 1 {
+		t.Fatalf("expected exactly one failure, got %v", failed)
+	}
+
+	if success := permits.releasedWith[nodes[0].Host]; success {
+		t.Fatalf("expected permit to be released with success=false after a restart failure")
+	}
+}
+
+type failOnceRestarter struct {
+	mu        sync.Mutex
+	count     int
+	failAfter int
+}
+
+func (r *failOnceRestarter) RestartNode(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node) error {
+	r.mu.Lock()
+	r.count++
+	count := r.count
+	r.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	if count == r.failAfter {
+		return fmt.Errorf("simulated restart failure")
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package restarters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/ydb-platform/ydb-ops/internal/util"
+)
+
+var RestartTransports = []string{"ssh", "systemd", "script", "kubectl"}
+
+const DefaultRestartTransport = "ssh"
+
+// baremetalOpts is embedded by every baremetal-style restarter and carries
+// the options shared across all of them: how to reach a host and which
+// transport to reach it with.
+type baremetalOpts struct {
+	sshArgs           []string
+	kikimrStorageUnit bool
+
+	restartTransport string
+
+	systemd SystemdTransportOpts
+	script  ScriptTransportOpts
+	kubectl KubectlExecTransportOpts
+
+	health HealthGateOpts
+
+	detectSystemdUnit bool
+	ydbdPort          int
+	units             *unitCache
+}
+
+func (o *baremetalOpts) DefineFlags(fs *pflag.FlagSet) {
+	fs.StringArrayVar(&o.sshArgs, "ssh-args", []string{},
+		"Additional arguments passed to the ssh/pssh/nssh command used by the ssh transport")
+
+	fs.BoolVar(&o.kikimrStorageUnit, "kikimr", false,
+		`Use the legacy "kikimr" systemd unit name instead of ydb-server-storage.service`)
+
+	fs.StringVarP(&o.restartTransport, "restart-transport", "", DefaultRestartTransport,
+		fmt.Sprintf("How to reach a node to restart its systemd unit. Available choices: %s", strings.Join(RestartTransports, ", ")))
+
+	fs.BoolVar(&o.detectSystemdUnit, "detect-systemd-unit", false,
+		`Detect the systemd unit actually listening on --ydbd-port instead of guessing between
+ydb-server-storage.service and kikimr`)
+
+	fs.IntVar(&o.ydbdPort, "ydbd-port", DefaultStorageYdbdPort,
+		"Port ydbd listens on, used to resolve its systemd unit when --detect-systemd-unit is set")
+
+	o.systemd.DefineFlags(fs)
+	o.script.DefineFlags(fs)
+	o.kubectl.DefineFlags(fs)
+	o.health.DefineFlags(fs)
+}
+
+func (o *baremetalOpts) Validate() error {
+	if !util.Contains(RestartTransports, o.restartTransport) {
+		return fmt.Errorf("specified not supported restart transport: %s", o.restartTransport)
+	}
+
+	if o.restartTransport == "script" {
+		if err := o.script.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := o.health.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Transport builds the RestartTransport selected by --restart-transport.
+func (o *baremetalOpts) Transport() RestartTransport {
+	switch o.restartTransport {
+	case "systemd":
+		return NewSystemdTransport(o.systemd)
+	case "script":
+		return NewScriptTransport(o.script)
+	case "kubectl":
+		return NewKubectlExecTransport(o.kubectl)
+	default:
+		return NewSSHTransport(o.sshArgs)
+	}
+}
+
+// StorageBaremetalOpts configures StorageBaremetalRestarter.
+type StorageBaremetalOpts struct {
+	baremetalOpts
+}
@@ -0,0 +1,173 @@
+package restarters
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/ydb-platform/ydb-go-genproto/Ydb_Discovery_V1"
+	"github.com/ydb-platform/ydb-go-genproto/draft/protos/Ydb_Maintenance"
+	"github.com/ydb-platform/ydb-go-genproto/protos/Ydb_Discovery"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	DefaultHealthCheckTimeout   = 2 * time.Minute
+	DefaultHealthCheckInterval  = 2 * time.Second
+	DefaultHealthCheckMinStable = 3
+)
+
+// HealthChecker probes a single node and reports whether it is ready to
+// serve, following restartNodeBySystemdUnit/the chosen RestartTransport
+// reporting that the unit came back up. Implementations must treat ctx
+// cancellation as "give up", not "healthy".
+type HealthChecker interface {
+	Check(ctx context.Context, node *Ydb_Maintenance.Node) error
+}
+
+// HealthGateOpts configures the post-restart health-gate that runs before a
+// node's CMS maintenance permit is released.
+type HealthGateOpts struct {
+	disabled  bool
+	timeout   time.Duration
+	interval  time.Duration
+	minStable int
+
+	grpcPort int
+	httpPort int
+	useHTTP  bool
+}
+
+func (o *HealthGateOpts) DefineFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.disabled, "no-health-check", false,
+		"Skip the post-restart health check and trust the restart transport's exit code, e.g. for dry runs")
+
+	fs.DurationVar(&o.timeout, "health-check-timeout", DefaultHealthCheckTimeout,
+		"How long to wait for a restarted node to become healthy before counting the attempt as failed")
+
+	fs.DurationVar(&o.interval, "health-check-interval", DefaultHealthCheckInterval,
+		"How often to probe a restarted node while waiting for it to become healthy")
+
+	fs.IntVar(&o.minStable, "health-check-min-stable", DefaultHealthCheckMinStable,
+		"Consecutive successful probes required before a restarted node is considered healthy")
+
+	fs.IntVar(&o.grpcPort, "health-check-grpc-port", 2135,
+		"gRPC port used by the default health checker to call Ydb.Discovery.ListEndpoints")
+
+	fs.IntVar(&o.httpPort, "health-check-http-port", 8765,
+		"HTTP port used by the default health checker to poll the /actors/whiteboard monitoring endpoint")
+
+	fs.BoolVar(&o.useHTTP, "health-check-http", false,
+		"Use the HTTP whiteboard monitoring endpoint instead of the gRPC discovery endpoint for health checks")
+}
+
+func (o *HealthGateOpts) Validate() error {
+	if o.timeout < 0 {
+		return fmt.Errorf("specified invalid health check timeout: %s. Must be positive", o.timeout)
+	}
+
+	if o.interval <= 0 {
+		return fmt.Errorf("specified invalid health check interval: %s. Must be positive", o.interval)
+	}
+
+	if o.minStable < 1 {
+		return fmt.Errorf("specified invalid health check min stable count: %d. Must be at least 1", o.minStable)
+	}
+
+	return nil
+}
+
+func (o *HealthGateOpts) Checker() HealthChecker {
+	if o.useHTTP {
+		return &HTTPHealthChecker{port: o.httpPort}
+	}
+
+	return &GRPCHealthChecker{port: o.grpcPort}
+}
+
+// waitUntilHealthy polls checker until it reports minStable consecutive
+// successes for node, or returns an error once timeout elapses. It mirrors
+// fleetctl's waitForUnitsToRestart/assertUnitRestart: a restart is only
+// trusted once the node has proven itself ready, not merely that the
+// restart command exited zero.
+func waitUntilHealthy(ctx context.Context, logger *zap.SugaredLogger, checker HealthChecker, node *Ydb_Maintenance.Node, opts HealthGateOpts) error {
+	if opts.disabled {
+		logger.Debugf("Health check disabled, trusting restart transport for %s", node.Host)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	stable := 0
+	for {
+		if err := checker.Check(ctx, node); err != nil {
+			logger.Debugf("Health probe for %s not ready yet: %v", node.Host, err)
+			stable = 0
+		} else {
+			stable++
+			logger.Debugf("Health probe for %s succeeded (%d/%d stable)", node.Host, stable, opts.minStable)
+			if stable >= opts.minStable {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("node %s did not become healthy within %s: %w", node.Host, opts.timeout, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// HTTPHealthChecker polls a node's /actors/whiteboard monitoring endpoint.
+type HTTPHealthChecker struct {
+	port int
+}
+
+func (c *HTTPHealthChecker) Check(ctx context.Context, node *Ydb_Maintenance.Node) error {
+	url := fmt.Sprintf("http://%s:%d/actors/whiteboard", node.Host, c.port)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("whiteboard endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GRPCHealthChecker calls Ydb.Discovery.ListEndpoints on a node's gRPC
+// port: a successful response implies the node's gRPC stack came back up.
+type GRPCHealthChecker struct {
+	port int
+}
+
+func (c *GRPCHealthChecker) Check(ctx context.Context, node *Ydb_Maintenance.Node) error {
+	conn, err := grpc.NewClient(fmt.Sprintf("%s:%d", node.Host, c.port), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := Ydb_Discovery_V1.NewDiscoveryServiceClient(conn)
+
+	_, err = client.ListEndpoints(ctx, &Ydb_Discovery.ListEndpointsRequest{})
+	return err
+}
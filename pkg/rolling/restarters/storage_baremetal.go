@@ -1,6 +1,7 @@
 package restarters
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 
@@ -8,6 +9,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// StorageBaremetalRestarter implements NodeRestarter: the rolling.Batch that
+// drives it owns retrying per the RestartPolicy and recording each attempt
+// into RestartHistory, so RestartNode itself stays a single, non-retrying
+// attempt and just reports whether that attempt succeeded.
 type StorageBaremetalRestarter struct {
 	Opts *StorageBaremetalOpts
 }
@@ -31,7 +36,7 @@ func stripCommandFromArgs(args []string) (string, []string) {
 	return command, remainingSshArgs
 }
 
-func restartNodeBySystemdUnit(logger *zap.SugaredLogger, node *Ydb_Maintenance.Node, unitName string, sshArgs []string) error {
+func restartNodeBySystemdUnit(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node, unitName string, sshArgs []string) error {
 	logger.Debugf("Restarting %s systemd unit", unitName)
 
 	remoteRestartCommand := fmt.Sprintf(
@@ -52,7 +57,7 @@ func restartNodeBySystemdUnit(logger *zap.SugaredLogger, node *Ydb_Maintenance.N
 		return fmt.Errorf("Supported ssh commands: ssh, pssh, nssh. Specified: %s", sshCommand)
 	}
 
-	cmd := exec.Command(sshCommand, fullSSHArgs...)
+	cmd := exec.CommandContext(ctx, sshCommand, fullSSHArgs...)
 
 	stdout, _ := cmd.StdoutPipe()
 	stderr, _ := cmd.StderrPipe()
@@ -73,29 +78,37 @@ func restartNodeBySystemdUnit(logger *zap.SugaredLogger, node *Ydb_Maintenance.N
 	return nil
 }
 
-func (r StorageBaremetalRestarter) RestartNode(logger *zap.SugaredLogger, node *Ydb_Maintenance.Node) error {
-	logger.Infof("Restarting storage node %s with ssh-args %v", node.Host, r.Opts.sshArgs)
-
-	// It is theoretically possible to guess the systemd-unit, but it is a fragile
-	// solution. tarasov-egor@ will keep it here during development time for reference:
-	//
-	// YDBD_PORT=2135
-	// YDBD_PID=$(sudo lsof -i :$YDBD_PORT | grep LISTEN | awk '{print $2}' | head -n 1)
-	// YDBD_UNIT=$(sudo ps -A -o'pid,unit' | grep $YDBD_PID | awk '{print $2}')
-	// sudo systemctl restart $YDBD_UNIT
+func (r StorageBaremetalRestarter) RestartNode(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node) error {
+	logger.Infof("Restarting storage node %s over %s transport", node.Host, r.Opts.restartTransport)
 
 	systemdUnitName := defaultStorageSystemdUnit
 	if r.Opts.kikimrStorageUnit {
 		systemdUnitName = internalStorageSystemdUnit
 	}
 
-	return restartNodeBySystemdUnit(logger, node, systemdUnitName, r.Opts.sshArgs)
+	transport := r.Opts.Transport()
+
+	if r.Opts.detectSystemdUnit {
+		systemdUnitName = DetectSystemdUnit(ctx, logger, transport, r.Opts.units, node, r.Opts.ydbdPort, systemdUnitName)
+	}
+
+	if err := transport.RestartUnit(ctx, logger, node, systemdUnitName); err != nil {
+		return err
+	}
+
+	if err := waitUntilHealthy(ctx, logger, r.Opts.health.Checker(), node, r.Opts.health); err != nil {
+		return fmt.Errorf("node %s restarted but failed its post-restart health check: %w", node.Host, err)
+	}
+
+	return nil
 }
 
 func NewStorageBaremetalRestarter() *StorageBaremetalRestarter {
 	return &StorageBaremetalRestarter{
 		Opts: &StorageBaremetalOpts{
-			baremetalOpts: baremetalOpts{},
+			baremetalOpts: baremetalOpts{
+				units: newUnitCache(),
+			},
 		},
 	}
 }
@@ -111,5 +124,20 @@ func (r StorageBaremetalRestarter) Filter(
 
 	logger.Debugf("Storage Baremetal Restarter selected following nodes for restart: %v", selectedNodes)
 
-	return selectedNodes
-}
\ No newline at end of file
+	// A misconfigured transport for one node should not take the whole
+	// invocation down: exclude that node from the rollout and let the
+	// caller decide what to do with a smaller (or empty) node list, rather
+	// than calling logger.Fatalf and killing the process here where
+	// nothing can react to it.
+	transport := r.Opts.Transport()
+	probedNodes := make([]*Ydb_Maintenance.Node, 0, len(selectedNodes))
+	for _, node := range selectedNodes {
+		if err := transport.Probe(context.Background(), node); err != nil {
+			logger.Errorf("excluding %s from this rollout: restart-transport %s is misconfigured: %v", node.Host, r.Opts.restartTransport, err)
+			continue
+		}
+		probedNodes = append(probedNodes, node)
+	}
+
+	return probedNodes
+}
@@ -0,0 +1,87 @@
+package restarters
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ydb-platform/ydb-go-genproto/draft/protos/Ydb_Maintenance"
+	"go.uber.org/zap"
+)
+
+// RestartTransport abstracts how a restarter reaches a node to restart one
+// of its systemd units: over ssh, directly via D-Bus, through a
+// user-supplied script, or by deleting its pod via kubectl (which ignores
+// the unit). Restarters select a
+// transport and stay agnostic of how the unit actually gets restarted.
+type RestartTransport interface {
+	// RestartUnit restarts unit on node, honoring ctx cancellation.
+	RestartUnit(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node, unit string) error
+
+	// Probe checks that the transport is usable for node, so Filter can
+	// fail fast instead of discovering misconfiguration mid-rollout.
+	Probe(ctx context.Context, node *Ydb_Maintenance.Node) error
+
+	// RunCommand runs an arbitrary shell command on node and returns its
+	// trimmed stdout. Transports that cannot execute ad-hoc commands (e.g.
+	// the systemd D-Bus transport) return an error, which callers such as
+	// unit detection treat as "unsupported, fall back to the static default".
+	RunCommand(ctx context.Context, node *Ydb_Maintenance.Node, command string) (string, error)
+}
+
+// SSHTransport is the original transport: it shells out to ssh, pssh or nssh
+// and runs `sudo systemctl restart <unit>` on the remote host.
+type SSHTransport struct {
+	sshArgs []string
+}
+
+func NewSSHTransport(sshArgs []string) *SSHTransport {
+	return &SSHTransport{sshArgs: sshArgs}
+}
+
+func (t *SSHTransport) RestartUnit(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node, unit string) error {
+	return restartNodeBySystemdUnit(ctx, logger, node, unit, t.sshArgs)
+}
+
+func (t *SSHTransport) RunCommand(ctx context.Context, node *Ydb_Maintenance.Node, command string) (string, error) {
+	sshCommand, remainingSshArgs := stripCommandFromArgs(t.sshArgs)
+
+	args := append([]string{"run"}, remainingSshArgs...)
+	switch sshCommand {
+	case "ssh":
+		args = append(args, node.Host, command)
+	case "nssh", "pssh":
+		args = append(args, command, node.Host)
+	default:
+		return "", fmt.Errorf("supported ssh commands: ssh, pssh, nssh. Specified: %s", sshCommand)
+	}
+
+	out, err := exec.CommandContext(ctx, sshCommand, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run command on %s: %w", node.Host, err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (t *SSHTransport) Probe(ctx context.Context, node *Ydb_Maintenance.Node) error {
+	sshCommand, remainingSshArgs := stripCommandFromArgs(t.sshArgs)
+
+	args := append([]string{"run"}, remainingSshArgs...)
+	switch sshCommand {
+	case "ssh":
+		args = append(args, node.Host, "true")
+	case "nssh", "pssh":
+		args = append(args, "true", node.Host)
+	default:
+		return fmt.Errorf("supported ssh commands: ssh, pssh, nssh. Specified: %s", sshCommand)
+	}
+
+	cmd := exec.CommandContext(ctx, sshCommand, args...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to reach %s over %s: %w", node.Host, sshCommand, err)
+	}
+
+	return nil
+}
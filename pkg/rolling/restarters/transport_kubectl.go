@@ -0,0 +1,105 @@
+package restarters
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/ydb-platform/ydb-go-genproto/draft/protos/Ydb_Maintenance"
+	"go.uber.org/zap"
+)
+
+// KubectlExecTransportOpts configures KubectlExecTransport.
+type KubectlExecTransportOpts struct {
+	namespace string
+	container string
+	context   string
+}
+
+func (o *KubectlExecTransportOpts) DefineFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.namespace, "kubectl-namespace", "default",
+		"Namespace of the YDB pods, used by the kubectl transport")
+
+	fs.StringVar(&o.container, "kubectl-container", "ydbd",
+		"Container to exec into within the YDB pod, used by the kubectl transport")
+
+	fs.StringVar(&o.context, "kubectl-context", "",
+		"kubectl context to use, used by the kubectl transport. Empty uses the current context")
+}
+
+// KubectlExecTransport restarts a YDB pod the k8s-native way: deleting it
+// and letting its owning StatefulSet/controller recreate it, since the ydbd
+// container images this transport targets run ydbd as PID 1 with no
+// systemd or sudo available to exec into. node.Host is expected to be the
+// pod name (or resolve to it, e.g. via the headless service FQDN used by
+// StatefulSets). exec is still used for Probe/RunCommand (unit detection),
+// which assume a shell is reachable in the container even though restart
+// itself no longer does.
+type KubectlExecTransport struct {
+	opts KubectlExecTransportOpts
+}
+
+func NewKubectlExecTransport(opts KubectlExecTransportOpts) *KubectlExecTransport {
+	return &KubectlExecTransport{opts: opts}
+}
+
+func (t *KubectlExecTransport) args(node *Ydb_Maintenance.Node, command ...string) []string {
+	args := []string{"exec", node.Host, "--namespace", t.opts.namespace, "--container", t.opts.container}
+	if t.opts.context != "" {
+		args = append(args, "--context", t.opts.context)
+	}
+
+	args = append(args, "--", "sh", "-c")
+	return append(args, fmt.Sprintf("%s", command[0]))
+}
+
+func (t *KubectlExecTransport) deleteArgs(node *Ydb_Maintenance.Node) []string {
+	args := []string{"delete", "pod", node.Host, "--namespace", t.opts.namespace, "--wait=true"}
+	if t.opts.context != "" {
+		args = append(args, "--context", t.opts.context)
+	}
+
+	return args
+}
+
+func (t *KubectlExecTransport) RestartUnit(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node, unit string) error {
+	logger.Debugf("Deleting pod %s to trigger a restart (unit %q ignored: pods have no systemd)", node.Host, unit)
+
+	cmd := exec.CommandContext(ctx, "kubectl", t.deleteArgs(node)...)
+
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start kubectl delete pod for %s: %w", node.Host, err)
+	}
+
+	go StreamPipeIntoLogger(stdout, logger)
+	go StreamPipeIntoLogger(stderr, logger)
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("kubectl delete pod failed for %s: %w", node.Host, err)
+	}
+
+	return nil
+}
+
+func (t *KubectlExecTransport) RunCommand(ctx context.Context, node *Ydb_Maintenance.Node, command string) (string, error) {
+	out, err := exec.CommandContext(ctx, "kubectl", t.args(node, command)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run command in pod %s: %w", node.Host, err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (t *KubectlExecTransport) Probe(ctx context.Context, node *Ydb_Maintenance.Node) error {
+	cmd := exec.CommandContext(ctx, "kubectl", t.args(node, "true")...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to exec into pod %s: %w", node.Host, err)
+	}
+
+	return nil
+}
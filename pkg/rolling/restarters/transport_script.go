@@ -0,0 +1,107 @@
+package restarters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"text/template"
+
+	"github.com/spf13/pflag"
+	"github.com/ydb-platform/ydb-go-genproto/draft/protos/Ydb_Maintenance"
+	"go.uber.org/zap"
+)
+
+// ScriptTransportOpts configures ScriptTransport.
+type ScriptTransportOpts struct {
+	command string
+}
+
+func (o *ScriptTransportOpts) DefineFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.command, "restart-script", "",
+		`Command template used by the script transport, with {{.Host}} and {{.Unit}} substituted in,
+e.g. "my-restart-hook.sh --host {{.Host}} --unit {{.Unit}}"`)
+}
+
+func (o *ScriptTransportOpts) Validate() error {
+	if o.command == "" {
+		return fmt.Errorf("--restart-script is required when --restart-transport=script")
+	}
+
+	if _, err := parseScriptTemplate(o.command); err != nil {
+		return fmt.Errorf("invalid --restart-script template: %w", err)
+	}
+
+	return nil
+}
+
+func parseScriptTemplate(command string) (*template.Template, error) {
+	return template.New("restart-script").Parse(command)
+}
+
+type scriptTemplateArgs struct {
+	Host string
+	Unit string
+}
+
+// ScriptTransport shells out to a user-supplied command template, the same
+// way external OCI runtime hooks are modeled in Docker/Podman: ydbops does
+// not need to know how the restart happens, only that the script exits
+// zero on success.
+type ScriptTransport struct {
+	opts ScriptTransportOpts
+}
+
+func NewScriptTransport(opts ScriptTransportOpts) *ScriptTransport {
+	return &ScriptTransport{opts: opts}
+}
+
+func (t *ScriptTransport) render(node *Ydb_Maintenance.Node, unit string) (string, error) {
+	tmpl, err := parseScriptTemplate(t.opts.command)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, scriptTemplateArgs{Host: node.Host, Unit: unit}); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (t *ScriptTransport) RestartUnit(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node, unit string) error {
+	rendered, err := t.render(node, unit)
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("Running restart script on %s: %s", node.Host, rendered)
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", rendered)
+
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start restart script for %s: %w", node.Host, err)
+	}
+
+	go StreamPipeIntoLogger(stdout, logger)
+	go StreamPipeIntoLogger(stderr, logger)
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("restart script failed for %s: %w", node.Host, err)
+	}
+
+	return nil
+}
+
+func (t *ScriptTransport) RunCommand(ctx context.Context, node *Ydb_Maintenance.Node, command string) (string, error) {
+	return "", fmt.Errorf("script transport does not support ad-hoc command execution")
+}
+
+func (t *ScriptTransport) Probe(ctx context.Context, node *Ydb_Maintenance.Node) error {
+	_, err := t.render(node, "probe.service")
+	return err
+}
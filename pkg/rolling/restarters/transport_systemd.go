@@ -0,0 +1,93 @@
+package restarters
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/spf13/pflag"
+	"github.com/ydb-platform/ydb-go-genproto/draft/protos/Ydb_Maintenance"
+	"go.uber.org/zap"
+)
+
+// SystemdTransportOpts configures SystemdTransport.
+type SystemdTransportOpts struct {
+	address string
+}
+
+func (o *SystemdTransportOpts) DefineFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.address, "systemd-address", "",
+		`D-Bus address to dial for the systemd transport, e.g. "unix:path=/run/dbus/system_bus_socket" on a local
+agent host. Empty uses the default system bus connection.`)
+}
+
+// SystemdTransport restarts a unit by talking to systemd directly over
+// D-Bus, for hosts reachable locally or via an agent co-located with the
+// node (it does not itself tunnel to a remote host).
+type SystemdTransport struct {
+	opts SystemdTransportOpts
+}
+
+func NewSystemdTransport(opts SystemdTransportOpts) *SystemdTransport {
+	return &SystemdTransport{opts: opts}
+}
+
+func (t *SystemdTransport) connect(ctx context.Context) (*dbus.Conn, error) {
+	if t.opts.address == "" {
+		return dbus.NewSystemConnectionContext(ctx)
+	}
+
+	return dbus.NewConnection(func() (*godbus.Conn, error) {
+		conn, err := godbus.Dial(t.opts.address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial D-Bus address %s: %w", t.opts.address, err)
+		}
+
+		if err := conn.Auth(nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to authenticate against D-Bus address %s: %w", t.opts.address, err)
+		}
+
+		return conn, nil
+	})
+}
+
+func (t *SystemdTransport) RestartUnit(ctx context.Context, logger *zap.SugaredLogger, node *Ydb_Maintenance.Node, unit string) error {
+	conn, err := t.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to systemd over D-Bus for %s: %w", node.Host, err)
+	}
+	defer conn.Close()
+
+	logger.Debugf("Restarting %s systemd unit over D-Bus", unit)
+
+	resultCh := make(chan string, 1)
+	if _, err := conn.RestartUnitContext(ctx, unit, "replace", resultCh); err != nil {
+		return fmt.Errorf("failed to restart unit %s on %s: %w", unit, node.Host, err)
+	}
+
+	select {
+	case result := <-resultCh:
+		if result != "done" {
+			return fmt.Errorf("restarting unit %s on %s finished with result %q", unit, node.Host, result)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *SystemdTransport) RunCommand(ctx context.Context, node *Ydb_Maintenance.Node, command string) (string, error) {
+	return "", fmt.Errorf("systemd transport does not support ad-hoc command execution")
+}
+
+func (t *SystemdTransport) Probe(ctx context.Context, node *Ydb_Maintenance.Node) error {
+	conn, err := t.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to systemd over D-Bus for %s: %w", node.Host, err)
+	}
+	defer conn.Close()
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package restarters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ydb-platform/ydb-go-genproto/draft/protos/Ydb_Maintenance"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultStorageYdbdPort = 2135
+	DefaultDynamicYdbdPort = 2136
+)
+
+// detectUnitProbeTemplate is the recipe described inline in
+// StorageBaremetalRestarter.RestartNode before it was implemented: find the
+// pid listening on the ydbd port, then the systemd unit that owns that pid.
+const detectUnitProbeTemplate = `sudo lsof -i :%d | grep LISTEN | awk '{print $2}' | head -n 1 | xargs -r sudo ps -o unit= -p`
+
+// unitCache remembers the detected unit per host for the lifetime of a
+// single ydbops invocation, so --detect-systemd-unit only pays the probe
+// cost once per node.
+type unitCache struct {
+	mu    sync.Mutex
+	units map[string]string
+}
+
+func newUnitCache() *unitCache {
+	return &unitCache{units: make(map[string]string)}
+}
+
+func (c *unitCache) get(host string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	unit, ok := c.units[host]
+	return unit, ok
+}
+
+func (c *unitCache) set(host, unit string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.units[host] = unit
+}
+
+// DetectSystemdUnit resolves the systemd unit actually listening on port on
+// node by shipping a small bash probe over transport, caching the result in
+// cache. It falls back to defaultUnit and logs the reason at debug level if
+// the probe can't be run or its output can't be parsed. It is shared by
+// every restarter that needs unit discovery, not just the baremetal one.
+func DetectSystemdUnit(ctx context.Context, logger *zap.SugaredLogger, transport RestartTransport, cache *unitCache, node *Ydb_Maintenance.Node, port int, defaultUnit string) string {
+	if unit, ok := cache.get(node.Host); ok {
+		logger.Debugf("Using cached systemd unit %q for %s", unit, node.Host)
+		return unit
+	}
+
+	probeCommand := fmt.Sprintf(detectUnitProbeTemplate, port)
+	logger.Debugf("Probing systemd unit on %s: %s", node.Host, probeCommand)
+
+	unit, err := transport.RunCommand(ctx, node, probeCommand)
+	if err != nil || unit == "" {
+		logger.Debugf("Failed to detect systemd unit on %s, falling back to %q: %v", node.Host, defaultUnit, err)
+		cache.set(node.Host, defaultUnit)
+		return defaultUnit
+	}
+
+	logger.Debugf("Detected systemd unit %q on %s", unit, node.Host)
+	cache.set(node.Host, unit)
+
+	return unit
+}